@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/thirdeyenick/nfs-test/internal/broker"
+)
+
+var (
+	brokerPublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "broker_publish_duration_seconds",
+		Help: "Time spent publishing a write event to the broker.",
+	}, []string{"result"})
+
+	brokerRoundtripDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "broker_roundtrip_duration_seconds",
+		Help: "Time between publishing a write event and observing it come back through the broker subscription.",
+	}, []string{"result"})
+
+	brokerRoundtripTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "broker_roundtrip_total",
+		Help: "Published write events, by whether they round-tripped back through the broker within bound.",
+	}, []string{"result"})
+)
+
+// brokerProbe publishes a broker.Event for every storage write and confirms
+// its own writes reappear through the broker's subscription within
+// roundtripBound. This turns the pod from a per-pod smoke test into a
+// distributed consistency probe: a missed or late round-trip points at NFS
+// clients on different nodes seeing stale directory entries or files.
+type brokerProbe struct {
+	b              broker.Broker
+	podName        string
+	logger         *slog.Logger
+	roundtripBound time.Duration
+
+	seq atomic.Uint64
+
+	mu      sync.Mutex
+	pending map[uint64]time.Time // seq -> publish time
+}
+
+func newBrokerProbe(ctx context.Context, brokerURL, podName string, logger *slog.Logger, roundtripBound time.Duration) (*brokerProbe, error) {
+	b, err := broker.New(brokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("can not set up broker: %w", err)
+	}
+
+	events, err := b.Subscribe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("can not subscribe to broker: %w", err)
+	}
+
+	p := &brokerProbe{
+		b:              b,
+		podName:        podName,
+		logger:         logger,
+		roundtripBound: roundtripBound,
+		pending:        map[uint64]time.Time{},
+	}
+	go p.consume(ctx, events)
+	return p, nil
+}
+
+// publish announces a write event for key/data and remembers its sequence
+// number so the round-trip can be matched up and measured in observe. The
+// sequence number, not the checksum, is what disambiguates events: writers
+// with a small payload space (e.g. the sqlite writer's bounded random
+// values) would otherwise collide on Checksum alone.
+func (p *brokerProbe) publish(ctx context.Context, key string, data []byte) {
+	event := broker.Event{
+		Pod:      p.podName,
+		Key:      key,
+		Ts:       time.Now(),
+		Checksum: broker.Checksum(data),
+		Seq:      p.seq.Add(1),
+	}
+
+	p.mu.Lock()
+	p.pending[event.Seq] = event.Ts
+	p.mu.Unlock()
+
+	start := time.Now()
+	result := "ok"
+	if err := p.b.Publish(ctx, event); err != nil {
+		result = "error"
+		p.logger.Error("broker publish error", slog.Any("error", err))
+	}
+	brokerPublishDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+}
+
+func (p *brokerProbe) consume(ctx context.Context, events <-chan broker.Event) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if event.Pod != p.podName {
+				continue
+			}
+			p.observe(event)
+		}
+	}
+}
+
+func (p *brokerProbe) observe(event broker.Event) {
+	p.mu.Lock()
+	publishedAt, known := p.pending[event.Seq]
+	if known {
+		delete(p.pending, event.Seq)
+	}
+	p.mu.Unlock()
+	if !known {
+		return
+	}
+
+	latency := time.Since(publishedAt)
+	result := "within_bound"
+	if latency > p.roundtripBound {
+		result = "late"
+		p.logger.Warn("broker roundtrip exceeded bound", "key", event.Key, "latency", latency)
+	}
+	brokerRoundtripDuration.WithLabelValues(result).Observe(latency.Seconds())
+	brokerRoundtripTotal.WithLabelValues(result).Inc()
+}
+
+func (p *brokerProbe) close() error {
+	return p.b.Close()
+}
@@ -0,0 +1,104 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// amqpExchange is the fanout exchange all pods publish write events to and
+// each bind an exclusive queue to.
+const amqpExchange = "nfs-test.write-events"
+
+func init() {
+	Register("amqp", newAmqpBroker)
+}
+
+type amqpBroker struct {
+	conn *amqp.Connection
+	ch   *amqp.Channel
+}
+
+func newAmqpBroker(dsn *url.URL) (Broker, error) {
+	conn, err := amqp.Dial(dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("amqp broker: dial: %w", err)
+	}
+	ch, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("amqp broker: open channel: %w", err)
+	}
+	if err := ch.ExchangeDeclare(amqpExchange, "fanout", true, false, false, false, nil); err != nil {
+		ch.Close()
+		conn.Close()
+		return nil, fmt.Errorf("amqp broker: declare exchange: %w", err)
+	}
+	return &amqpBroker{conn: conn, ch: ch}, nil
+}
+
+func (b *amqpBroker) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("amqp broker: marshal event: %w", err)
+	}
+	err = b.ch.PublishWithContext(ctx, amqpExchange, "", false, false, amqp.Publishing{
+		ContentType: "application/json",
+		Body:        data,
+	})
+	if err != nil {
+		return fmt.Errorf("amqp broker: publish: %w", err)
+	}
+	return nil
+}
+
+func (b *amqpBroker) Subscribe(ctx context.Context) (<-chan Event, error) {
+	q, err := b.ch.QueueDeclare("", false, true, true, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("amqp broker: declare queue: %w", err)
+	}
+	if err := b.ch.QueueBind(q.Name, "", amqpExchange, false, nil); err != nil {
+		return nil, fmt.Errorf("amqp broker: bind queue: %w", err)
+	}
+	deliveries, err := b.ch.Consume(q.Name, "", true, true, false, false, nil)
+	if err != nil {
+		return nil, fmt.Errorf("amqp broker: consume: %w", err)
+	}
+
+	events := make(chan Event, 16)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case d, ok := <-deliveries:
+				if !ok {
+					return
+				}
+				var event Event
+				if err := json.Unmarshal(d.Body, &event); err != nil {
+					continue
+				}
+				select {
+				case events <- event:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return events, nil
+}
+
+func (b *amqpBroker) Close() error {
+	if err := b.ch.Close(); err != nil {
+		return fmt.Errorf("amqp broker: close channel: %w", err)
+	}
+	if err := b.conn.Close(); err != nil {
+		return fmt.Errorf("amqp broker: close connection: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/nats-io/nats.go"
+)
+
+// natsSubject is the single subject all pods publish write events to and
+// subscribe from.
+const natsSubject = "nfs-test.write-events"
+
+func init() {
+	Register("nats", newNatsBroker)
+}
+
+type natsBroker struct {
+	conn *nats.Conn
+	sub  *nats.Subscription
+}
+
+func newNatsBroker(dsn *url.URL) (Broker, error) {
+	conn, err := nats.Connect(dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("nats broker: connect: %w", err)
+	}
+	return &natsBroker{conn: conn}, nil
+}
+
+func (b *natsBroker) Publish(_ context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("nats broker: marshal event: %w", err)
+	}
+	if err := b.conn.Publish(natsSubject, data); err != nil {
+		return fmt.Errorf("nats broker: publish: %w", err)
+	}
+	return nil
+}
+
+func (b *natsBroker) Subscribe(ctx context.Context) (<-chan Event, error) {
+	events := make(chan Event, 16)
+	sub, err := b.conn.Subscribe(natsSubject, func(msg *nats.Msg) {
+		var event Event
+		if err := json.Unmarshal(msg.Data, &event); err != nil {
+			return
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("nats broker: subscribe: %w", err)
+	}
+	b.sub = sub
+	return events, nil
+}
+
+func (b *natsBroker) Close() error {
+	if b.sub != nil {
+		if err := b.sub.Unsubscribe(); err != nil {
+			return fmt.Errorf("nats broker: unsubscribe: %w", err)
+		}
+	}
+	b.conn.Close()
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/exp/rand"
+)
+
+// loadConfig configures a run of the background NFS load generator.
+type loadConfig struct {
+	workers  int
+	qps      float64
+	mode     string // write|read|list|mixed
+	keySpace int
+}
+
+// newLoadConfig parses the LOAD_* env variables, falling back to modest
+// defaults for anything left unset.
+func newLoadConfig(workersEnv, qpsEnv, modeEnv, keySpaceEnv string) (loadConfig, error) {
+	cfg := loadConfig{workers: 4, qps: 10, mode: "mixed", keySpace: 100}
+
+	if workersEnv != "" {
+		n, err := strconv.Atoi(workersEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("can not parse LOAD_WORKERS: %w", err)
+		}
+		cfg.workers = n
+	}
+	if qpsEnv != "" {
+		q, err := strconv.ParseFloat(qpsEnv, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("can not parse LOAD_QPS: %w", err)
+		}
+		cfg.qps = q
+	}
+	if modeEnv != "" {
+		switch modeEnv {
+		case "write", "read", "list", "mixed":
+			cfg.mode = modeEnv
+		default:
+			return cfg, fmt.Errorf("invalid LOAD_MODE %q (want write, read, list or mixed)", modeEnv)
+		}
+	}
+	if keySpaceEnv != "" {
+		n, err := strconv.Atoi(keySpaceEnv)
+		if err != nil {
+			return cfg, fmt.Errorf("can not parse LOAD_KEY_SPACE: %w", err)
+		}
+		cfg.keySpace = n
+	}
+
+	if cfg.workers <= 0 {
+		return cfg, fmt.Errorf("LOAD_WORKERS must be positive, got %d", cfg.workers)
+	}
+	return cfg, nil
+}
+
+// loadGenerator fans N workers, each with their own Storage backend, out
+// against the configured NFS share at a bounded aggregate QPS, so operators
+// can stress the server from a pod instead of firing requests by hand.
+type loadGenerator struct {
+	parentCtx context.Context
+	logger    *slog.Logger
+
+	mu      sync.Mutex
+	running bool
+	cfg     loadConfig
+	cancel  context.CancelFunc
+	stats   *loadStats
+}
+
+func newLoadGenerator(ctx context.Context, logger *slog.Logger) *loadGenerator {
+	return &loadGenerator{parentCtx: ctx, logger: logger}
+}
+
+func (l *loadGenerator) start(cfg loadConfig) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running {
+		return errors.New("load generator is already running")
+	}
+
+	// Each worker gets its own Storage backend (the same nfs driver
+	// /read-storage and /write-storage use), rather than hand-rolling a
+	// second nfs4 client wrapper. Build and validate the first one
+	// synchronously, so a bad UID/GID or an unreachable NFS server
+	// surfaces as an error from /load/start instead of workers silently
+	// dying in the background.
+	nfsDsn := "nfs://" + server + share
+	probeStore, err := NewStorage(nfsDsn)
+	if err != nil {
+		return fmt.Errorf("load generator: %w", err)
+	}
+	connectCtx, cancel := context.WithTimeout(l.parentCtx, 20*time.Second)
+	_, err = probeStore.List(connectCtx, "/")
+	cancel()
+	if err != nil {
+		return fmt.Errorf("load generator: can not connect to NFS server: %w", err)
+	}
+
+	ctx, cancelRun := context.WithCancel(l.parentCtx)
+	stats := newLoadStats()
+	perWorkerQps := cfg.qps / float64(cfg.workers)
+	go runLoadWorker(ctx, 0, cfg, perWorkerQps, stats, l.logger, probeStore)
+	for i := 1; i < cfg.workers; i++ {
+		store, err := NewStorage(nfsDsn)
+		if err != nil {
+			l.logger.Error("load worker: can not create storage backend", "worker", i, slog.Any("error", err))
+			continue
+		}
+		go runLoadWorker(ctx, i, cfg, perWorkerQps, stats, l.logger, store)
+	}
+
+	l.cfg = cfg
+	l.cancel = cancelRun
+	l.stats = stats
+	l.running = true
+	return nil
+}
+
+func (l *loadGenerator) stop() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.running {
+		return errors.New("load generator is not running")
+	}
+	l.cancel()
+	l.running = false
+	return nil
+}
+
+// loadStatus is the JSON body returned by /load/status.
+type loadStatus struct {
+	Running bool            `json:"running"`
+	Workers int             `json:"workers,omitempty"`
+	Qps     float64         `json:"qps,omitempty"`
+	Mode    string          `json:"mode,omitempty"`
+	Ops     []opPercentiles `json:"ops,omitempty"`
+}
+
+func (l *loadGenerator) status() loadStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := loadStatus{Running: l.running}
+	if l.running {
+		status.Workers = l.cfg.workers
+		status.Qps = l.cfg.qps
+		status.Mode = l.cfg.mode
+	}
+	if l.stats != nil {
+		status.Ops = l.stats.snapshot()
+	}
+	return status
+}
+
+func runLoadWorker(ctx context.Context, id int, cfg loadConfig, qps float64, stats *loadStats, logger *slog.Logger, store Storage) {
+	interval := time.Second
+	if qps > 0 {
+		interval = time.Duration(float64(time.Second) / qps)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	rng := rand.New(rand.NewSource(uint64(id)))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			op := pickLoadOp(cfg.mode, rng)
+			key := fmt.Sprintf("load/worker-%d/%d", id, rng.Intn(cfg.keySpace))
+			opStart := time.Now()
+			opErr := runLoadOp(ctx, store, op, key)
+			stats.record(op, time.Since(opStart), opErr)
+		}
+	}
+}
+
+func pickLoadOp(mode string, rng *rand.Rand) string {
+	if mode != "mixed" {
+		return mode
+	}
+	switch rng.Intn(3) {
+	case 0:
+		return "write"
+	case 1:
+		return "read"
+	default:
+		return "list"
+	}
+}
+
+func runLoadOp(ctx context.Context, store Storage, op, key string) error {
+	switch op {
+	case "write":
+		return store.Write(ctx, key, []byte(time.Now().Format(time.RFC3339Nano)))
+	case "read":
+		_, err := store.Read(ctx, key)
+		return err
+	case "list":
+		_, err := store.List(ctx, "/")
+		return err
+	default:
+		return fmt.Errorf("unknown load mode %q", op)
+	}
+}
+
+// loadStatsSampleCap bounds the per-op latency samples kept for percentile
+// reporting, so a long-running load doesn't grow memory unbounded.
+const loadStatsSampleCap = 1000
+
+// loadStats aggregates per-op counts, errors and a bounded window of recent
+// latencies across all load workers.
+type loadStats struct {
+	mu      sync.Mutex
+	counts  map[string]int64
+	errors  map[string]int64
+	samples map[string][]time.Duration
+}
+
+func newLoadStats() *loadStats {
+	return &loadStats{
+		counts:  map[string]int64{},
+		errors:  map[string]int64{},
+		samples: map[string][]time.Duration{},
+	}
+}
+
+func (s *loadStats) record(op string, d time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.counts[op]++
+	if err != nil {
+		s.errors[op]++
+		return
+	}
+	samples := s.samples[op]
+	if len(samples) >= loadStatsSampleCap {
+		samples = samples[1:]
+	}
+	s.samples[op] = append(samples, d)
+}
+
+// opPercentiles summarizes one op's load-generator stats for /load/status.
+type opPercentiles struct {
+	Op     string  `json:"op"`
+	Count  int64   `json:"count"`
+	Errors int64   `json:"errors"`
+	P50Ms  float64 `json:"p50_ms"`
+	P95Ms  float64 `json:"p95_ms"`
+	P99Ms  float64 `json:"p99_ms"`
+}
+
+func (s *loadStats) snapshot() []opPercentiles {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ops := make([]string, 0, len(s.counts))
+	for op := range s.counts {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+
+	result := make([]opPercentiles, 0, len(ops))
+	for _, op := range ops {
+		samples := append([]time.Duration(nil), s.samples[op]...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		result = append(result, opPercentiles{
+			Op:     op,
+			Count:  s.counts[op],
+			Errors: s.errors[op],
+			P50Ms:  percentileMs(samples, 0.50),
+			P95Ms:  percentileMs(samples, 0.95),
+			P99Ms:  percentileMs(samples, 0.99),
+		})
+	}
+	return result
+}
+
+func percentileMs(sorted []time.Duration, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(p * float64(len(sorted)))
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return float64(sorted[idx].Microseconds()) / 1000
+}
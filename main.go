@@ -8,20 +8,24 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
-	"strconv"
 	"strings"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"database/sql"
 
-	"github.com/Cyberax/go-nfs-client/nfs4"
-	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"golang.org/x/exp/rand"
 )
 
+// storageTimeKey is the key under which /write-storage and /read-storage
+// store the last-written timestamp in the configured Storage backend.
+const storageTimeKey = "current-time"
+
 type FileInfo struct {
 	Name  string    `json:"name"`
 	IsDir bool      `json:"is_dir"`
@@ -38,16 +42,30 @@ var (
 	uidEnv                 = os.Getenv("UID")
 	gidEnv                 = os.Getenv("GID")
 	storagePathEnv         = os.Getenv("STORAGE_PATH")
+	storageDsnEnv          = os.Getenv("STORAGE_DSN")
 	sqliteStoragePathEnv   = os.Getenv("SQLITE_STORAGE_PATH")
 	podNameEnv             = os.Getenv("POD_NAME")
 	sqliteWriteIntervalEnv = os.Getenv("WRITE_INTERVAL")
 	sqliteReadIntervalEnv  = os.Getenv("READ_INTERVAL")
+
+	sqliteBackupIntervalEnv = os.Getenv("SQLITE_BACKUP_INTERVAL")
+	sqliteBackupEveryNEnv   = os.Getenv("SQLITE_BACKUP_EVERY_N_WRITES")
+	backupS3EndpointEnv     = os.Getenv("BACKUP_S3_ENDPOINT")
+	backupS3BucketEnv       = os.Getenv("BACKUP_S3_BUCKET")
+	backupS3PrefixEnv       = os.Getenv("BACKUP_S3_PREFIX")
+	backupS3AccessKeyEnv    = os.Getenv("BACKUP_S3_ACCESS_KEY")
+	backupS3SecretKeyEnv    = os.Getenv("BACKUP_S3_SECRET_KEY")
+
+	brokerUrlEnv            = os.Getenv("BROKER_URL")
+	brokerRoundtripBoundEnv = os.Getenv("BROKER_ROUNDTRIP_BOUND")
+
+	loadWorkersEnv  = os.Getenv("LOAD_WORKERS")
+	loadQpsEnv      = os.Getenv("LOAD_QPS")
+	loadModeEnv     = os.Getenv("LOAD_MODE")
+	loadKeySpaceEnv = os.Getenv("LOAD_KEY_SPACE")
 )
 
 func main() {
-	if server == "" || share == "" {
-		log.Fatal("Must set NFS_SERVER and NFS_SHARE environment variables")
-	}
 	if listenPort == "" {
 		listenPort = ":8080"
 	}
@@ -63,19 +81,37 @@ func main() {
 	if sqliteReadIntervalEnv == "" {
 		sqliteReadIntervalEnv = "10s"
 	}
+	if brokerRoundtripBoundEnv == "" {
+		brokerRoundtripBoundEnv = "10s"
+	}
 
 	timeout, err := time.ParseDuration(timeoutEnv)
 	if err != nil {
 		log.Fatalf("timeout can not be parsed: %v", err)
 	}
+	brokerRoundtripBound, err := time.ParseDuration(brokerRoundtripBoundEnv)
+	if err != nil {
+		log.Fatalf("broker roundtrip bound can not be parsed: %v", err)
+	}
 
-	uid, err := strconv.Atoi(uidEnv)
+	if storageDsnEnv == "" {
+		if storagePathEnv != "" {
+			storageDsnEnv = "file://" + storagePathEnv
+		} else {
+			storageDsnEnv = "nfs://" + server + share
+		}
+	}
+	storageDsnURL, err := url.Parse(storageDsnEnv)
 	if err != nil {
-		log.Fatalf("can not parse UID env variable: %v", err)
+		log.Fatalf("can not parse STORAGE_DSN: %v", err)
+	}
+	if storageDsnURL.Scheme == "nfs" && (server == "" || share == "") {
+		log.Fatal("Must set NFS_SERVER and NFS_SHARE environment variables (or point STORAGE_DSN at a non-nfs backend)")
 	}
-	gid, err := strconv.Atoi(gidEnv)
+
+	store, err := NewStorage(storageDsnEnv)
 	if err != nil {
-		log.Fatalf("can not parse GID env variable: %v", err)
+		log.Fatalf("can not set up storage backend: %v", err)
 	}
 
 	logger := slog.Default().With("pod_name", podNameEnv)
@@ -92,13 +128,35 @@ func main() {
 		cancel()
 	}()
 
+	// setup the write-event broker probe, if configured
+	var probe *brokerProbe
+	if brokerUrlEnv != "" {
+		p, err := newBrokerProbe(ctx, brokerUrlEnv, podNameEnv, logger, brokerRoundtripBound)
+		if err != nil {
+			log.Fatalf("can not set up broker probe: %v", err)
+		}
+		probe = p
+		defer func() {
+			if err := probe.close(); err != nil {
+				logger.Error("can not close broker probe", slog.Any("error", err))
+			}
+		}()
+	}
+
 	// setup sqlite routines
+	var backup *sqliteBackupper
 	if strings.TrimSpace(sqliteStoragePathEnv) != "" {
-		closeDB, err := setupSqlite(ctx, sqliteStoragePathEnv, podNameEnv, logger, sqliteReadIntervalEnv, sqliteWriteIntervalEnv)
+		backupCfg, err := newSqliteBackupConfig(sqliteBackupIntervalEnv, sqliteBackupEveryNEnv, backupS3EndpointEnv, backupS3BucketEnv, backupS3PrefixEnv, backupS3AccessKeyEnv, backupS3SecretKeyEnv)
+		if err != nil {
+			log.Fatalf("can not parse sqlite backup configuration: %v", err)
+		}
+
+		closeDB, b, err := setupSqlite(ctx, sqliteStoragePathEnv, podNameEnv, logger, sqliteReadIntervalEnv, sqliteWriteIntervalEnv, backupCfg, probe)
 		if err != nil {
 			log.Fatalf("error when setting up sqlite tests: %v", err)
 			return
 		}
+		backup = b
 		defer func() {
 			if err := closeDB(); err != nil {
 				logger.Error("can not close DB", slog.Any("error", err))
@@ -106,42 +164,99 @@ func main() {
 		}()
 	}
 
+	loadGen := newLoadGenerator(ctx, logger)
+
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
 		w.WriteHeader(http.StatusOK)
+		observeOp("http_root", "http", start, nil)
 	})
 
-	http.HandleFunc("/write-storage", func(w http.ResponseWriter, r *http.Request) {
-		response := &response{writer: w, logger: logger}
+	http.Handle("/metrics", promhttp.Handler())
+
+	http.HandleFunc("/load/start", func(w http.ResponseWriter, r *http.Request) {
+		response := &response{writer: w, logger: logger, op: "load-start", start: time.Now()}
+		defer response.handle()
+
+		loadCfg, err := newLoadConfig(loadWorkersEnv, loadQpsEnv, loadModeEnv, loadKeySpaceEnv)
+		if err != nil {
+			response.err = fmt.Errorf("invalid load generator configuration: %w", err)
+			return
+		}
+		if err := loadGen.start(loadCfg); err != nil {
+			response.err = err
+			return
+		}
+		response.Message = fmt.Sprintf("load generator started with %d workers at %.1f qps (mode=%s)", loadCfg.workers, loadCfg.qps, loadCfg.mode)
+	})
+
+	http.HandleFunc("/load/stop", func(w http.ResponseWriter, r *http.Request) {
+		response := &response{writer: w, logger: logger, op: "load-stop", start: time.Now()}
+		defer response.handle()
+
+		if err := loadGen.stop(); err != nil {
+			response.err = err
+			return
+		}
+		response.Message = "load generator stopped"
+	})
+
+	http.HandleFunc("/load/status", func(w http.ResponseWriter, r *http.Request) {
+		response := &response{writer: w, logger: logger, op: "load-status", start: time.Now()}
 		defer response.handle()
 
-		if storagePathEnv == "" {
-			response.err = errors.New("no storage path set via STORAGE_PATH env variable")
+		status := loadGen.status()
+		response.Status = &status
+	})
+
+	http.HandleFunc("/backup", func(w http.ResponseWriter, r *http.Request) {
+		response := &response{writer: w, logger: logger, op: "backup", start: time.Now()}
+		defer response.handle()
+
+		if backup == nil {
+			response.err = errors.New("no sqlite backup configured via BACKUP_S3_BUCKET env variable")
 			return
 		}
+
+		backupCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		key, err := backup.backupNow(backupCtx)
+		if err != nil {
+			response.err = fmt.Errorf("error backing up sqlite db: %w", err)
+			return
+		}
+		response.Message = fmt.Sprintf("sqlite db backed up to %s", key)
+	})
+
+	http.HandleFunc("/write-storage", func(w http.ResponseWriter, r *http.Request) {
+		response := &response{writer: w, logger: logger, op: "write-storage", start: time.Now()}
+		defer response.handle()
+
 		// Get the current time
 		currentTime := time.Now().Format(time.RFC3339)
 
-		// Write time to file
-		err := os.WriteFile(storagePathEnv, []byte(currentTime), 0644)
-		if err != nil {
-			response.err = fmt.Errorf("error writing to file: %w", err)
+		writeCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		if err := store.Write(writeCtx, storageTimeKey, []byte(currentTime)); err != nil {
+			response.err = fmt.Errorf("error writing to storage: %w", err)
 			return
 		}
+		if probe != nil {
+			probe.publish(writeCtx, storageTimeKey, []byte(currentTime))
+		}
 		response.Message = fmt.Sprintf("current time written to store: %s", currentTime)
 		return
 	})
 
 	http.HandleFunc("/read-storage", func(w http.ResponseWriter, r *http.Request) {
-		response := &response{writer: w, logger: logger}
+		response := &response{writer: w, logger: logger, op: "read-storage", start: time.Now()}
 		defer response.handle()
 
-		if storagePathEnv == "" {
-			response.err = errors.New("no storage path set via STORAGE_PATH env variable")
-			return
-		}
-		content, err := os.ReadFile(storagePathEnv)
+		readCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		content, err := store.Read(readCtx, storageTimeKey)
 		if err != nil {
-			response.err = fmt.Errorf("error reading from file: %w", err)
+			response.err = fmt.Errorf("error reading from storage: %w", err)
 			return
 		}
 		response.Message = fmt.Sprintf("last time written to store: %s", string(content))
@@ -149,31 +264,20 @@ func main() {
 	})
 
 	http.HandleFunc("/list", func(w http.ResponseWriter, r *http.Request) {
-		response := &response{writer: w, logger: logger}
+		response := &response{writer: w, logger: logger, op: "list", start: time.Now()}
 		defer response.handle()
 
-		initCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		listCtx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		auth := nfs4.AuthParams{
-			Uid:         uint32(uid),
-			Gid:         uint32(gid),
-			MachineName: machineName,
-		}
-		client, err := nfs4.NewNfsClient(initCtx, server, auth)
-		if err != nil {
-			response.err = fmt.Errorf("error creating NFS client: %w", err)
-			return
-		}
-
 		path := r.URL.Query().Get("path")
 		if path == "" {
 			path = "/"
 		}
 
-		entries, err := client.GetFileList(path)
+		entries, err := store.List(listCtx, path)
 		if err != nil {
-			response.err = fmt.Errorf("Error reading dir %q: %v", path, err)
+			response.err = fmt.Errorf("error listing %q: %w", path, err)
 			return
 		}
 		response.Files = entries
@@ -216,10 +320,13 @@ type response struct {
 	Pod     string          `json:"pod,omitempty"`
 	Error   string          `json:"error,omitempty"`
 	Message string          `json:"message,omitempty"`
-	Files   []nfs4.FileInfo `json:"files,omitempty"`
+	Files   []FileInfo      `json:"files,omitempty"`
+	Status  *loadStatus     `json:"status,omitempty"`
 	writer  http.ResponseWriter
 	logger  *slog.Logger
 	err     error
+	op      string    // HTTP handler name, for op_duration_seconds/op_errors_total
+	start   time.Time // when the handler started, for op_duration_seconds
 }
 
 func (r *response) handle() {
@@ -233,36 +340,43 @@ func (r *response) handle() {
 	} else {
 		r.writer.WriteHeader(http.StatusOK)
 	}
+	if r.op != "" {
+		observeOp("http_"+r.op, "http", r.start, r.err)
+	}
 	if err := json.NewEncoder(r.writer).Encode(r); err != nil {
 		r.logger.Error("can not JSON encode output", slog.Any("error", err))
 	}
 }
-func setupSqlite(ctx context.Context, storagePath string, podName string, logger *slog.Logger, sqliteReadIntervalEnv string, sqliteWriteIntervalEnv string) (func() error, error) {
+func setupSqlite(ctx context.Context, storagePath string, podName string, logger *slog.Logger, sqliteReadIntervalEnv string, sqliteWriteIntervalEnv string, backupCfg sqliteBackupConfig, probe *brokerProbe) (func() error, *sqliteBackupper, error) {
 	sqliteReadInterval, err := time.ParseDuration(sqliteReadIntervalEnv)
 	if err != nil {
-		return nil, fmt.Errorf("can not parse sqlite read interval env variable: %w", err)
+		return nil, nil, fmt.Errorf("can not parse sqlite read interval env variable: %w", err)
 	}
 	sqliteWriteInterval, err := time.ParseDuration(sqliteWriteIntervalEnv)
 	if err != nil {
-		return nil, fmt.Errorf("can not parse sqlite write interval env variable: %w", err)
+		return nil, nil, fmt.Errorf("can not parse sqlite write interval env variable: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", storagePath)
+	db, err := sql.Open(sqliteDriver, storagePath)
 	if err != nil {
-		return nil, fmt.Errorf("can not open sqlite db: %w", err)
+		return nil, nil, fmt.Errorf("can not open sqlite db: %w", err)
 	}
 
-	_, err = db.Exec(`
-        CREATE TABLE IF NOT EXISTS entries (
-            id INTEGER PRIMARY KEY AUTOINCREMENT,
-            pod TEXT NOT NULL,
-            ts DATETIME NOT NULL,
-            payload TEXT
-        )`)
-	if err != nil {
-		return db.Close, fmt.Errorf("can not create schema: %w", err)
+	if err := createSchema(db); err != nil {
+		return db.Close, nil, err
 	}
 
+	var backup *sqliteBackupper
+	if backupCfg.enabled() {
+		backup, err = newSqliteBackupper(db, podName, logger, backupCfg)
+		if err != nil {
+			return db.Close, nil, fmt.Errorf("can not set up sqlite backup: %w", err)
+		}
+		go backup.run(ctx)
+	}
+
+	var lastWrite atomic.Value // holds time.Time
+
 	// write to sqlite
 	go func() {
 		for {
@@ -271,12 +385,22 @@ func setupSqlite(ctx context.Context, storagePath string, podName string, logger
 				return
 			default:
 				payload := fmt.Sprintf("rand=%d", rand.Intn(1000))
+				start := time.Now()
 				_, err := db.Exec(
 					"INSERT INTO entries (pod, ts, payload) VALUES (?, datetime('now'), ?)",
 					podName, payload,
 				)
+				observeOp("sqlite_insert", "sqlite", start, err)
 				if err != nil {
 					logger.Error("WRITE error", slog.Any("error", err))
+				} else {
+					lastWrite.Store(time.Now())
+					if backup != nil {
+						backup.recordWrite()
+					}
+					if probe != nil {
+						probe.publish(ctx, "sqlite:"+podName, []byte(payload))
+					}
 				}
 				time.Sleep(sqliteWriteInterval)
 			}
@@ -294,16 +418,40 @@ func setupSqlite(ctx context.Context, storagePath string, podName string, logger
 			case <-ticker.C:
 				var count int
 				var lastPod, lastTS, lastPayload string
+				start := time.Now()
 				err := db.QueryRow(
 					"SELECT COUNT(*), (SELECT pod FROM entries ORDER BY id DESC LIMIT 1), (SELECT ts FROM entries ORDER BY id DESC LIMIT 1), (SELECT payload FROM entries ORDER BY id DESC LIMIT 1)",
 				).Scan(&count, &lastPod, &lastTS, &lastPayload)
+				observeOp("sqlite_select", "sqlite", start, err)
 				if err != nil {
 					logger.Error("read error", slog.Any("error", err))
 				} else {
 					logger.Info("successful read", "count", count, "lastPod", lastPod, "lastTimestamp", lastTS, "lastPayload", lastPayload)
+					sqliteRowCount.Set(float64(count))
+					if lw, ok := lastWrite.Load().(time.Time); ok {
+						sqliteLastWriteAge.Set(time.Since(lw).Seconds())
+					}
 				}
 			}
 		}
 	}()
-	return db.Close, nil
+	return db.Close, backup, nil
+}
+
+// createSchema creates the entries table setupSqlite reads and writes,
+// if it doesn't already exist. It is split out from setupSqlite so both
+// the cgo (sqlite3) and purego (modernc.org/sqlite) builds of sqliteDriver
+// can be exercised against it directly in tests.
+func createSchema(db *sql.DB) error {
+	_, err := db.Exec(`
+        CREATE TABLE IF NOT EXISTS entries (
+            id INTEGER PRIMARY KEY AUTOINCREMENT,
+            pod TEXT NOT NULL,
+            ts DATETIME NOT NULL,
+            payload TEXT
+        )`)
+	if err != nil {
+		return fmt.Errorf("can not create schema: %w", err)
+	}
+	return nil
 }
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	opDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "op_duration_seconds",
+		Help: "Duration of storage, sqlite and HTTP operations.",
+	}, []string{"op", "backend", "result"})
+
+	opErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "op_errors_total",
+		Help: "Count of failed storage, sqlite and HTTP operations.",
+	}, []string{"op", "backend", "code"})
+
+	sqliteRowCount = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sqlite_row_count",
+		Help: "Number of rows currently in the sqlite entries table.",
+	})
+
+	sqliteLastWriteAge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "sqlite_last_successful_write_age_seconds",
+		Help: "Seconds since the last successful sqlite write.",
+	})
+)
+
+// observeOp records a duration histogram observation for op/backend, and
+// bumps the error counter (labeled with a coarse error code) when err is
+// non-nil.
+func observeOp(op, backend string, start time.Time, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+		opErrors.WithLabelValues(op, backend, errorCode(err)).Inc()
+	}
+	opDuration.WithLabelValues(op, backend, result).Observe(time.Since(start).Seconds())
+}
+
+func errorCode(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	default:
+		return "error"
+	}
+}
+
+// instrumentedStorage wraps a Storage backend so every call is recorded as
+// an op_duration_seconds/op_errors_total observation labeled with the
+// backend's DSN scheme.
+type instrumentedStorage struct {
+	backend Storage
+	scheme  string
+}
+
+func (s *instrumentedStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	data, err := s.backend.Read(ctx, key)
+	observeOp("storage_read", s.scheme, start, err)
+	return data, err
+}
+
+func (s *instrumentedStorage) Write(ctx context.Context, key string, data []byte) error {
+	start := time.Now()
+	err := s.backend.Write(ctx, key, data)
+	observeOp("storage_write", s.scheme, start, err)
+	return err
+}
+
+func (s *instrumentedStorage) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	start := time.Now()
+	entries, err := s.backend.List(ctx, prefix)
+	observeOp("storage_list", s.scheme, start, err)
+	return entries, err
+}
+
+func (s *instrumentedStorage) Delete(ctx context.Context, key string) error {
+	start := time.Now()
+	err := s.backend.Delete(ctx, key)
+	observeOp("storage_delete", s.scheme, start, err)
+	return err
+}
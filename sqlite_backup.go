@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// sqliteBackupConfig holds the (optional) SQLite-to-S3 backup settings. A
+// zero value (empty bucket) means backups are disabled.
+type sqliteBackupConfig struct {
+	interval     time.Duration
+	everyNWrites int64
+	endpoint     string
+	bucket       string
+	prefix       string
+	accessKey    string
+	secretKey    string
+}
+
+// newSqliteBackupConfig parses the SQLITE_BACKUP_* and BACKUP_S3_* env
+// variables. Backups stay disabled unless BACKUP_S3_BUCKET is set.
+func newSqliteBackupConfig(intervalEnv, everyNEnv, endpoint, bucket, prefix, accessKey, secretKey string) (sqliteBackupConfig, error) {
+	cfg := sqliteBackupConfig{
+		endpoint:  endpoint,
+		bucket:    bucket,
+		prefix:    prefix,
+		accessKey: accessKey,
+		secretKey: secretKey,
+	}
+	if cfg.bucket == "" {
+		return cfg, nil
+	}
+
+	if intervalEnv == "" {
+		intervalEnv = "5m"
+	}
+	interval, err := time.ParseDuration(intervalEnv)
+	if err != nil {
+		return cfg, fmt.Errorf("can not parse SQLITE_BACKUP_INTERVAL env variable: %w", err)
+	}
+	cfg.interval = interval
+
+	if everyNEnv != "" {
+		everyN, err := strconv.ParseInt(everyNEnv, 10, 64)
+		if err != nil {
+			return cfg, fmt.Errorf("can not parse SQLITE_BACKUP_EVERY_N_WRITES env variable: %w", err)
+		}
+		cfg.everyNWrites = everyN
+	}
+	return cfg, nil
+}
+
+func (c sqliteBackupConfig) enabled() bool {
+	return c.bucket != ""
+}
+
+// sqliteBackupper snapshots the sqlite database via VACUUM INTO (so it never
+// blocks concurrent writers) and uploads the snapshot to an S3-compatible
+// bucket, either on a timer or after a configured number of writes.
+type sqliteBackupper struct {
+	db      *sql.DB
+	podName string
+	logger  *slog.Logger
+	cfg     sqliteBackupConfig
+	client  *s3.Client
+
+	writes  atomic.Int64
+	trigger chan struct{}
+}
+
+func newSqliteBackupper(db *sql.DB, podName string, logger *slog.Logger, cfg sqliteBackupConfig) (*sqliteBackupper, error) {
+	awsCfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(cfg.accessKey, cfg.secretKey, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("sqlite backup: load AWS config: %w", err)
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.endpoint)
+		}
+		o.UsePathStyle = true
+	})
+
+	return &sqliteBackupper{
+		db:      db,
+		podName: podName,
+		logger:  logger,
+		cfg:     cfg,
+		client:  client,
+		trigger: make(chan struct{}, 1),
+	}, nil
+}
+
+// run drives the periodic backup timer and reacts to recordWrite triggers
+// until ctx is cancelled.
+func (b *sqliteBackupper) run(ctx context.Context) {
+	ticker := time.NewTicker(b.cfg.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.runBackup(ctx)
+		case <-b.trigger:
+			b.runBackup(ctx)
+		}
+	}
+}
+
+func (b *sqliteBackupper) runBackup(ctx context.Context) {
+	key, err := b.backupNow(ctx)
+	if err != nil {
+		b.logger.Error("sqlite backup error", slog.Any("error", err))
+		return
+	}
+	b.logger.Info("sqlite backup uploaded", "key", key)
+}
+
+// recordWrite should be called after every successful insert; once
+// everyNWrites inserts have accumulated it nudges run to back up early
+// instead of waiting for the next tick.
+func (b *sqliteBackupper) recordWrite() {
+	if b.cfg.everyNWrites <= 0 {
+		return
+	}
+	if n := b.writes.Add(1); n%b.cfg.everyNWrites == 0 {
+		select {
+		case b.trigger <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// backupNow snapshots the database to a temp file and uploads it under a
+// timestamped key, returning that key.
+func (b *sqliteBackupper) backupNow(ctx context.Context) (string, error) {
+	tmpFile, err := os.CreateTemp("", "sqlite-backup-*.db")
+	if err != nil {
+		return "", fmt.Errorf("sqlite backup: create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	if err := tmpFile.Close(); err != nil {
+		return "", fmt.Errorf("sqlite backup: close temp file: %w", err)
+	}
+	// VACUUM INTO refuses to write to a file that already exists.
+	if err := os.Remove(tmpPath); err != nil {
+		return "", fmt.Errorf("sqlite backup: prepare temp file: %w", err)
+	}
+	defer os.Remove(tmpPath)
+
+	if _, err := b.db.ExecContext(ctx, "VACUUM INTO ?", tmpPath); err != nil {
+		return "", fmt.Errorf("sqlite backup: vacuum into %q: %w", tmpPath, err)
+	}
+
+	data, err := os.ReadFile(tmpPath)
+	if err != nil {
+		return "", fmt.Errorf("sqlite backup: read snapshot: %w", err)
+	}
+
+	key := fmt.Sprintf("%s%s-%s.db", b.keyPrefix(), b.podName, time.Now().UTC().Format("20060102T150405Z"))
+	_, err = b.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(b.cfg.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return "", fmt.Errorf("sqlite backup: upload %q: %w", key, err)
+	}
+	return key, nil
+}
+
+func (b *sqliteBackupper) keyPrefix() string {
+	if b.cfg.prefix == "" {
+		return ""
+	}
+	return strings.Trim(b.cfg.prefix, "/") + "/"
+}
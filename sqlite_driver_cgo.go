@@ -0,0 +1,11 @@
+//go:build !purego
+
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteDriver is the database/sql driver name setupSqlite opens the
+// database with. The default (CGO-enabled) build uses mattn/go-sqlite3.
+const sqliteDriver = "sqlite3"
@@ -0,0 +1,13 @@
+//go:build purego
+
+package main
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDriver is the database/sql driver name setupSqlite opens the
+// database with. The purego build (no CGO, e.g. for osusergo,netgo static
+// binaries on minimal Kubernetes images) uses modernc.org/sqlite instead of
+// mattn/go-sqlite3.
+const sqliteDriver = "sqlite"
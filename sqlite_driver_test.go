@@ -0,0 +1,44 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+// TestCreateSchema exercises createSchema and a basic insert/select
+// round-trip against whichever sqliteDriver this build was compiled with
+// (mattn/go-sqlite3 by default, modernc.org/sqlite under -tags purego).
+func TestCreateSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+
+	db, err := sql.Open(sqliteDriver, dbPath)
+	if err != nil {
+		t.Fatalf("sql.Open(%q): %v", sqliteDriver, err)
+	}
+	defer db.Close()
+
+	if err := createSchema(db); err != nil {
+		t.Fatalf("createSchema: %v", err)
+	}
+	// calling it twice must stay a no-op, since setupSqlite relies on
+	// CREATE TABLE IF NOT EXISTS to be safe to run on every start.
+	if err := createSchema(db); err != nil {
+		t.Fatalf("createSchema (second call): %v", err)
+	}
+
+	if _, err := db.Exec(
+		"INSERT INTO entries (pod, ts, payload) VALUES (?, datetime('now'), ?)",
+		"test-pod", "payload",
+	); err != nil {
+		t.Fatalf("insert into entries: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM entries").Scan(&count); err != nil {
+		t.Fatalf("select count from entries: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("count = %d, want 1", count)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+)
+
+// Storage is the common interface every storage backend (file, nfs, s3, ...)
+// implements, so that the HTTP handlers don't need to know which backend
+// they're talking to.
+type Storage interface {
+	Read(ctx context.Context, key string) ([]byte, error)
+	Write(ctx context.Context, key string, data []byte) error
+	List(ctx context.Context, prefix string) ([]FileInfo, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// StorageFactory builds a Storage backend from a parsed STORAGE_DSN.
+type StorageFactory func(dsn *url.URL) (Storage, error)
+
+var (
+	storageDriversMu sync.RWMutex
+	storageDrivers   = map[string]StorageFactory{}
+)
+
+// RegisterStorageDriver makes a storage backend available under the given DSN
+// scheme (e.g. "file", "nfs", "s3"). It is meant to be called from a driver's
+// init() function, mirroring how database/sql drivers register themselves.
+// It panics if factory is nil or scheme is already registered, since that is
+// always a programming error.
+func RegisterStorageDriver(scheme string, factory StorageFactory) {
+	storageDriversMu.Lock()
+	defer storageDriversMu.Unlock()
+	if factory == nil {
+		panic("storage: RegisterStorageDriver factory is nil")
+	}
+	if _, dup := storageDrivers[scheme]; dup {
+		panic("storage: RegisterStorageDriver called twice for scheme " + scheme)
+	}
+	storageDrivers[scheme] = factory
+}
+
+// NewStorage parses dsn and dispatches to the driver registered for its
+// scheme, e.g. "file:///var/lib/data", "nfs://192.168.1.50/export/data" or
+// "s3://my-bucket/prefix".
+func NewStorage(dsn string) (Storage, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("can not parse STORAGE_DSN: %w", err)
+	}
+	if u.Scheme == "" {
+		return nil, fmt.Errorf("STORAGE_DSN %q has no scheme", dsn)
+	}
+
+	storageDriversMu.RLock()
+	factory, ok := storageDrivers[u.Scheme]
+	storageDriversMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no storage driver registered for scheme %q", u.Scheme)
+	}
+
+	backend, err := factory(u)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStorage{backend: backend, scheme: u.Scheme}, nil
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+func init() {
+	RegisterStorageDriver("file", newFileStorage)
+}
+
+// fileStorage implements Storage on top of a local (or externally mounted,
+// e.g. NFS) directory tree, keyed by path relative to root.
+type fileStorage struct {
+	root string
+}
+
+func newFileStorage(dsn *url.URL) (Storage, error) {
+	root := dsn.Path
+	if root == "" {
+		root = dsn.Opaque
+	}
+	if root == "" {
+		return nil, fmt.Errorf("file storage DSN %q is missing a path", dsn.String())
+	}
+	return &fileStorage{root: root}, nil
+}
+
+func (s *fileStorage) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+func (s *fileStorage) Read(_ context.Context, key string) ([]byte, error) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		return nil, fmt.Errorf("file storage: read %q: %w", key, err)
+	}
+	return data, nil
+}
+
+func (s *fileStorage) Write(_ context.Context, key string, data []byte) error {
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("file storage: create parent dir for %q: %w", key, err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		return fmt.Errorf("file storage: write %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *fileStorage) List(_ context.Context, prefix string) ([]FileInfo, error) {
+	dir := s.path(prefix)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("file storage: list %q: %w", prefix, err)
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, fmt.Errorf("file storage: stat %q: %w", entry.Name(), err)
+		}
+		files = append(files, FileInfo{
+			Name:  entry.Name(),
+			IsDir: entry.IsDir(),
+			Size:  uint64(info.Size()),
+			Mtime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+func (s *fileStorage) Delete(_ context.Context, key string) error {
+	if err := os.Remove(s.path(key)); err != nil {
+		return fmt.Errorf("file storage: delete %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+)
+
+// TestFileStorageRoundTrip exercises fileStorage's Write/Read/List/Delete
+// against a throwaway directory, so a future backend change that breaks the
+// Storage contract fails here instead of only showing up against a real NFS
+// share.
+func TestFileStorageRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	store := &fileStorage{root: t.TempDir()}
+
+	data := []byte("hello world")
+	if err := store.Write(ctx, "dir/file.txt", data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+
+	got, err := store.Read(ctx, "dir/file.txt")
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if string(got) != string(data) {
+		t.Errorf("Read = %q, want %q", got, data)
+	}
+
+	files, err := store.List(ctx, "dir")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(files) != 1 || files[0].Name != "file.txt" || files[0].IsDir {
+		t.Errorf("List = %+v, want a single file.txt entry", files)
+	}
+
+	if err := store.Delete(ctx, "dir/file.txt"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := store.Read(ctx, "dir/file.txt"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Read after Delete: got err %v, want os.ErrNotExist", err)
+	}
+}
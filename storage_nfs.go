@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Cyberax/go-nfs-client/nfs4"
+)
+
+func init() {
+	RegisterStorageDriver("nfs", newNfsStorage)
+}
+
+// nfsStorage keeps a single nfs4 client alive for the lifetime of the
+// backend instead of dialing a fresh one per request, so /list,
+// /write-storage and /read-storage share one connection instead of
+// reconnecting on every call.
+type nfsStorage struct {
+	server  string
+	share   string
+	auth    nfs4.AuthParams
+	timeout time.Duration
+
+	mu     sync.Mutex
+	client *nfs4.NfsClient
+}
+
+func newNfsStorage(dsn *url.URL) (Storage, error) {
+	nfsServer := dsn.Host
+	if nfsServer == "" {
+		nfsServer = server
+	}
+	nfsShare := dsn.Path
+	if nfsShare == "" {
+		nfsShare = share
+	}
+	if nfsServer == "" || nfsShare == "" {
+		return nil, fmt.Errorf("nfs storage DSN %q is missing a server or share (set NFS_SERVER/NFS_SHARE or include them in STORAGE_DSN)", dsn.String())
+	}
+
+	timeout := 20 * time.Second
+	if timeoutEnv != "" {
+		parsed, err := time.ParseDuration(timeoutEnv)
+		if err != nil {
+			return nil, fmt.Errorf("timeout can not be parsed: %w", err)
+		}
+		timeout = parsed
+	}
+
+	auth, err := parseNfsAuth()
+	if err != nil {
+		return nil, err
+	}
+
+	return &nfsStorage{
+		server:  nfsServer,
+		share:   nfsShare,
+		auth:    auth,
+		timeout: timeout,
+	}, nil
+}
+
+// parseNfsAuth parses the UID/GID env variables shared by every nfs4 client
+// this binary creates (the nfs storage backend and the load generator
+// workers), so they fail the same way on a bad UID/GID instead of each
+// re-implementing the parsing.
+func parseNfsAuth() (nfs4.AuthParams, error) {
+	uid, err := strconv.Atoi(uidEnv)
+	if err != nil {
+		return nfs4.AuthParams{}, fmt.Errorf("can not parse UID env variable: %w", err)
+	}
+	gid, err := strconv.Atoi(gidEnv)
+	if err != nil {
+		return nfs4.AuthParams{}, fmt.Errorf("can not parse GID env variable: %w", err)
+	}
+	return nfs4.AuthParams{
+		Uid:         uint32(uid),
+		Gid:         uint32(gid),
+		MachineName: machineName,
+	}, nil
+}
+
+// connect lazily dials the NFS server on first use and reuses the resulting
+// client for every subsequent call.
+func (s *nfsStorage) connect(ctx context.Context) (*nfs4.NfsClient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client != nil {
+		return s.client, nil
+	}
+
+	connectCtx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	client, err := nfs4.NewNfsClient(connectCtx, s.server, s.auth)
+	if err != nil {
+		return nil, fmt.Errorf("nfs storage: connect to %q: %w", s.server, err)
+	}
+	s.client = client
+	return s.client, nil
+}
+
+func (s *nfsStorage) Read(ctx context.Context, key string) ([]byte, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err := client.ReadFileAll(key, &buf); err != nil {
+		return nil, fmt.Errorf("nfs storage: read %q: %w", key, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func (s *nfsStorage) Write(ctx context.Context, key string, data []byte) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.WriteFile(key, true, 0, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("nfs storage: write %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *nfsStorage) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if prefix == "" {
+		prefix = "/"
+	}
+
+	entries, err := client.GetFileList(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("nfs storage: list %q: %w", prefix, err)
+	}
+
+	files := make([]FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		files = append(files, FileInfo{
+			Name:  entry.Name,
+			IsDir: entry.IsDir,
+			Size:  entry.Size,
+			Mtime: entry.Mtime,
+		})
+	}
+	return files, nil
+}
+
+func (s *nfsStorage) Delete(ctx context.Context, key string) error {
+	client, err := s.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.DeleteFile(key); err != nil {
+		return fmt.Errorf("nfs storage: delete %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func init() {
+	RegisterStorageDriver("s3", newS3Storage)
+}
+
+// s3Storage implements Storage on top of an S3(-compatible) bucket, keyed by
+// object key relative to prefix.
+type s3Storage struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3Storage(dsn *url.URL) (Storage, error) {
+	if dsn.Host == "" {
+		return nil, fmt.Errorf("s3 storage DSN %q is missing a bucket", dsn.String())
+	}
+
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("s3 storage: load AWS config: %w", err)
+	}
+
+	return &s3Storage{
+		client: s3.NewFromConfig(cfg),
+		bucket: dsn.Host,
+		prefix: strings.Trim(dsn.Path, "/"),
+	}, nil
+}
+
+func (s *s3Storage) objectKey(key string) string {
+	if s.prefix == "" {
+		return key
+	}
+	return s.prefix + "/" + key
+}
+
+func (s *s3Storage) Read(ctx context.Context, key string) ([]byte, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 storage: read %q: %w", key, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("s3 storage: read %q: %w", key, err)
+	}
+	return data, nil
+}
+
+func (s *s3Storage) Write(ctx context.Context, key string, data []byte) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   bytes.NewReader(data),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 storage: write %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s *s3Storage) List(ctx context.Context, prefix string) ([]FileInfo, error) {
+	out, err := s.client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.objectKey(prefix)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3 storage: list %q: %w", prefix, err)
+	}
+
+	files := make([]FileInfo, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		files = append(files, FileInfo{
+			Name:  strings.TrimPrefix(aws.ToString(obj.Key), s.prefix+"/"),
+			IsDir: false,
+			Size:  uint64(aws.ToInt64(obj.Size)),
+			Mtime: aws.ToTime(obj.LastModified),
+		})
+	}
+	return files, nil
+}
+
+func (s *s3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return fmt.Errorf("s3 storage: delete %q: %w", key, err)
+	}
+	return nil
+}
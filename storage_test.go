@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+// TestNewStorageDispatch exercises the DSN scheme dispatch in NewStorage,
+// so a typo'd scheme or a missing registration surfaces as a test failure
+// instead of only at startup against a real backend.
+func TestNewStorageDispatch(t *testing.T) {
+	store, err := NewStorage("file://" + t.TempDir())
+	if err != nil {
+		t.Fatalf("NewStorage(file): %v", err)
+	}
+	if _, ok := store.(*instrumentedStorage); !ok {
+		t.Errorf("NewStorage(file) = %T, want *instrumentedStorage", store)
+	}
+
+	if _, err := NewStorage("bogus://somewhere"); err == nil {
+		t.Error("NewStorage(bogus) = nil error, want an error for an unregistered scheme")
+	}
+
+	if _, err := NewStorage("no-scheme"); err == nil {
+		t.Error("NewStorage(no-scheme) = nil error, want an error for a DSN with no scheme")
+	}
+}